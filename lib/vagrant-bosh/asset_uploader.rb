@@ -19,6 +19,12 @@ module VagrantPlugins
         end
       end
 
+      def upload_file(src_path, dst_path)
+        @ui.timed_msg(:upload, dst_path: dst_path) do
+          upload_path(src_path, dst_path)
+        end
+      end
+
       def upload_text(text, dst_path)
         @ui.timed_msg(:upload, dst_path: dst_path) do
           begin
@@ -45,6 +51,7 @@ module VagrantPlugins
           @c.mv(dst_tmp_path, dst_path)
           @c.chown("root", "root", dst_path, true)
         else
+          @c.mkdir_p(File.dirname(dst_path)) # create nested dst dir
           @c.mv(dst_tmp_path, dst_path)
           @c.chown("root", "root", dst_path)
         end
@@ -4,12 +4,13 @@ require "json"
 module VagrantPlugins
   module VagrantBosh
     class Bootstrapper
-      def initialize(communicator, config, asset_uploader, provisioner_tracker, manifest_factory)
+      def initialize(communicator, config, asset_uploader, provisioner_tracker, manifest_factory, applied_manifest)
         @c = communicator
         @config = config
         @asset_uploader = asset_uploader
         @provisioner_tracker = provisioner_tracker
         @manifest_factory = manifest_factory
+        @applied_manifest = applied_manifest
 
         @logger = Log4r::Logger.new("vagrant::provisioners::bosh::bootstrapper")
       end
@@ -20,13 +21,22 @@ module VagrantPlugins
         if @config.manifest
           manifest = @manifest_factory.new_manifest(@config.manifest)
           manifest.resolve_releases
-          @asset_uploader.upload_text(manifest.as_string, @config.manifest_path)
+          manifest_str = manifest.as_string
+
+          # Applied manifest is kept without interpolated secrets
+          applied_manifest_str = manifest.as_uninterpolated_string
+
+          @applied_manifest.show_diff(applied_manifest_str) unless applied_manifest_str.empty?
+          @asset_uploader.upload_text(manifest_str, @config.manifest_path)
         end
 
         config_json = JSON.dump(config_hash)
         @asset_uploader.upload_text(config_json, @config.config_path)
 
         run_provisioner
+
+        # Only remember manifest once provisioner succeeded
+        @applied_manifest.save(applied_manifest_str) if applied_manifest_str && !applied_manifest_str.empty?
       end
 
       private
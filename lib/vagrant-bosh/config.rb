@@ -14,6 +14,36 @@ module VagrantPlugins
       # Manifest holds full BOSH deployment manifest as a string.
       attr_accessor :manifest
 
+      # Manifest preprocessor is either `erb` to render manifest as an ERB template
+      # or a command (run in Vagrantfile directory) that gets manifest on stdin
+      # and prints preprocessed manifest to stdout.
+      attr_accessor :manifest_preprocessor
+
+      # Cloud config holds cloud config (networks, compilation, disk_types, etc.)
+      # as a string; its sections are merged into the manifest.
+      attr_accessor :cloud_config
+
+      # Ops files hold paths (relative to Vagrantfile) to go-patch style
+      # ops files that are applied in order to the manifest.
+      attr_accessor :ops_files
+
+      # Variables used to interpolate `((name))` placeholders in the manifest
+      # come from vars files (paths relative to Vagrantfile), environment variables
+      # prefixed with `<vars_env>_`, and explicitly given vars (in the increasing order of precedence).
+      attr_accessor :vars_files, :vars_env, :vars
+
+      # Vars store holds path (relative to Vagrantfile) to a JSON file where variables
+      # generated from manifest's `variables` section are kept between provisions.
+      attr_accessor :vars_store
+
+      # CredHub holds optional CredHub server configuration used to resolve
+      # variables not explicitly provided (url, client, client_secret, ca_cert, prefix).
+      attr_accessor :credhub
+
+      # Strict manifest reports unrecognized manifest keys
+      # either as warnings (`warn`) or as an error (`error`).
+      attr_accessor :strict_manifest
+
       # Full stemcell compatibility forces provisioner to install all 
       # (not just minimum) dependencies usually found on a stemcell.
       attr_accessor :full_stemcell_compatibility
@@ -25,6 +55,19 @@ module VagrantPlugins
       # because it creates locally versioned gems.
       attr_accessor :create_release_cmd
 
+      # Require release checksums makes provisioning fail when a release
+      # downloaded on the host does not declare `sha1` in the manifest.
+      attr_accessor :require_release_checksums
+
+      # Releases downloaded on the host are fetched in parallel, at most
+      # `release_download_concurrency` at a time, sharing overall
+      # `release_download_limit_rate` bandwidth limit (e.g. `2M` bytes per second).
+      attr_accessor :release_download_concurrency, :release_download_limit_rate
+
+      # Credentials used to pull `oci://` releases keyed by registry host
+      # (e.g. {"ghcr.io" => {"username" => "...", "password" => "..."}}).
+      attr_accessor :oci_credentials
+
       def initialize(*args)
         super
         @base_dir = "/opt/bosh-provisioner"
@@ -40,12 +83,54 @@ module VagrantPlugins
         @local_blobstore_dir = File.join(@base_dir, "blobstore")
         @synced_releases_dir = File.join(@base_dir, "synced-releases")
 
+        @ops_files = Array(@ops_files)
+
+        @vars_files = Array(@vars_files)
+        @vars ||= {}
+
         @full_stemcell_compatibility = !!@full_stemcell_compatibility
 
         @create_release_cmd ||= "ruby -v; bosh -n create release --force"
+
+        @require_release_checksums = !!@require_release_checksums
+
+        @release_download_concurrency ||= 4
+
+        @oci_credentials ||= {}
       end
 
       def validate(machine)
+        errors = _detected_errors
+
+        Array(@ops_files).each do |path|
+          unless File.file?(File.expand_path(path, machine.env.root_path))
+            errors << I18n.t("bosh.config.missing_ops_file", path: path)
+          end
+        end
+
+        Array(@vars_files).each do |path|
+          unless File.file?(File.expand_path(path, machine.env.root_path))
+            errors << I18n.t("bosh.config.missing_vars_file", path: path)
+          end
+        end
+
+        if @strict_manifest && !%w(warn error).include?(@strict_manifest.to_s)
+          errors << I18n.t("bosh.config.invalid_strict_manifest", value: @strict_manifest)
+        end
+
+        if @credhub
+          %w(url client client_secret).each do |key|
+            unless @credhub[key] || @credhub[key.to_sym]
+              errors << I18n.t("bosh.config.missing_credhub_option", key: key)
+            end
+          end
+        end
+
+        if @release_download_limit_rate && @release_download_limit_rate.to_s !~ /\A\d+[kmg]?\z/i
+          errors << I18n.t("bosh.config.invalid_release_download_limit_rate", value: @release_download_limit_rate)
+        end
+
+        { "bosh provisioner" => errors }
       end
     end
   end
@@ -0,0 +1,112 @@
+require "log4r"
+require "vagrant-bosh/deployment/deep_merge"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # Addons colocates jobs from manifest's `addons` section onto every
+      # deployment job matched by addon's include/exclude rules.
+      # Addon and addon job properties are deep merged into job properties
+      # overriding values that deployment job already specifies.
+      class Addons
+        RULE_KEYS = %w(deployments instance_groups jobs)
+
+        def initialize(ui)
+          @ui = ui.for(:deployment, :addons)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::addons")
+        end
+
+        def apply(manifest)
+          addons = manifest.delete("addons") || []
+
+          addons.each do |addon|
+            warn_unsupported_rule_keys(addon)
+
+            (manifest["jobs"] || []).each do |job|
+              next unless applies?(addon, manifest, job)
+
+              @ui.debug_msg(:apply, addon: addon["name"], job: job["name"])
+
+              templates = (job["templates"] ||= [])
+              addon_templates(addon).each do |template|
+                next if templates.any? { |t| t["name"] == template["name"] }
+                templates << template.reject { |k, _| k == "properties" }
+                job["properties"] = DeepMerge.merge(job["properties"] || {}, template["properties"] || {})
+              end
+
+              job["properties"] = DeepMerge.merge(job["properties"] || {}, addon["properties"] || {})
+            end
+          end
+
+          manifest
+        end
+
+        private
+
+        def addon_templates(addon)
+          addon["jobs"] || addon["templates"] || []
+        end
+
+        # Rule keys other than deployments, instance_groups and jobs
+        # (e.g. stemcell, azs, networks) are ignored.
+        def applies?(addon, manifest, job)
+          include_rule = addon["include"]
+          exclude_rule = addon["exclude"]
+
+          included =
+            if include_rule.nil?
+              true
+            else
+              include_criteria = criteria(include_rule, manifest, job)
+              # Empty rule matches everything; rule with only ignored keys matches nothing
+              include_criteria.empty? ? include_rule.empty? : include_criteria.all?
+            end
+
+          excluded =
+            if exclude_rule.nil?
+              false
+            else
+              exclude_criteria = criteria(exclude_rule, manifest, job)
+              !exclude_criteria.empty? && exclude_criteria.all?
+            end
+
+          included && !excluded
+        end
+
+        def criteria(rule, manifest, job)
+          criteria = []
+
+          if rule["deployments"]
+            criteria << rule["deployments"].include?(manifest["name"])
+          end
+
+          if rule["instance_groups"]
+            criteria << rule["instance_groups"].include?(job["name"])
+          end
+
+          if rule["jobs"]
+            templates = job["templates"] || []
+
+            criteria << rule["jobs"].any? { |rule_job|
+              templates.any? { |t| t["name"] == rule_job["name"] && t["release"] == rule_job["release"] }
+            }
+          end
+
+          criteria
+        end
+
+        def warn_unsupported_rule_keys(addon)
+          %w(include exclude).each do |rule_name|
+            keys = (addon[rule_name] || {}).keys - RULE_KEYS
+            next if keys.empty?
+
+            @ui.msg(:unsupported_rule_keys_warning,
+              addon: addon["name"], rule: rule_name, keys: keys.join(", "))
+          end
+        end
+      end
+
+      #~
+    end
+  end
+end
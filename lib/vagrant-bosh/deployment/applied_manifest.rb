@@ -0,0 +1,95 @@
+require "log4r"
+require "yaml"
+require "json"
+require "fileutils"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # AppliedManifest keeps the last successfully provisioned manifest
+      # on the host and shows how a new manifest differs from it.
+      class AppliedManifest
+        def initialize(path, ui)
+          @path = path
+
+          @ui = ui.for(:deployment, :applied_manifest)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::applied_manifest")
+        end
+
+        def show_diff(manifest_str)
+          return unless File.exist?(@path)
+
+          changes = ManifestDiff.new(YAML.load(File.read(@path)), YAML.load(manifest_str)).changes
+
+          if changes.empty?
+            @ui.msg(:no_changes, {})
+          else
+            @ui.msg(:changes, {})
+            changes.each { |change| @ui.msg(:change, change: change) }
+          end
+        end
+
+        def save(manifest_str)
+          FileUtils.mkdir_p(File.dirname(@path))
+          File.open(@path, "w", 0600) { |f| f.write(manifest_str) }
+        end
+      end
+
+      # ManifestDiff lists added (+), removed (-) and changed (~) keys
+      # between two parsed manifests. Arrays of named entries (e.g. jobs)
+      # are compared by name. Property values and secret-looking keys are redacted.
+      class ManifestDiff
+        SECRET_KEY_REGEX = /password|secret|private_key|token|credential|cert/i
+
+        def initialize(previous, current)
+          @previous = previous
+          @current = current
+        end
+
+        def changes
+          [].tap { |out| diff(@previous, @current, "", false, out) }
+        end
+
+        private
+
+        def diff(a, b, path, redact, out)
+          if a.is_a?(Hash) && b.is_a?(Hash)
+            diff_entries(a, b, path, redact, out) { |k| path.empty? ? k.to_s : "#{path}.#{k}" }
+          elsif named_array?(a) && named_array?(b)
+            a = Hash[a.map { |e| [e["name"], e] }]
+            b = Hash[b.map { |e| [e["name"], e] }]
+            diff_entries(a, b, path, redact, out) { |k| "#{path}[#{k}]" }
+          elsif a != b
+            out << "~ #{path}: #{format(a, redact)} -> #{format(b, redact)}"
+          end
+        end
+
+        def diff_entries(a, b, path, redact, out)
+          (a.keys | b.keys).each do |k|
+            child_path = yield(k)
+            child_redact = redact || k == "properties" || k.to_s =~ SECRET_KEY_REGEX
+
+            if !a.has_key?(k)
+              out << "+ #{child_path}: #{format(b[k], child_redact)}"
+            elsif !b.has_key?(k)
+              out << "- #{child_path}"
+            else
+              diff(a[k], b[k], child_path, child_redact, out)
+            end
+          end
+        end
+
+        def named_array?(value)
+          value.is_a?(Array) && value.all? { |e| e.is_a?(Hash) && e.has_key?("name") } &&
+            value.map { |e| e["name"] }.uniq.size == value.size
+        end
+
+        def format(value, redact)
+          redact ? "<redacted>" : JSON.dump(value)
+        end
+      end
+
+      #~
+    end
+  end
+end
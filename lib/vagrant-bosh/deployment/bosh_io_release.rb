@@ -0,0 +1,202 @@
+require "log4r"
+require "json"
+require "uri"
+require "net/http"
+require "fileutils"
+require "rubygems"
+require "vagrant/util/downloader"
+require "vagrant-bosh/errors"
+require "vagrant-bosh/deployment/uploadable_release"
+require "vagrant-bosh/deployment/release_checksum"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # BoshIoRelease represents a release published on bosh.io
+      # that is downloaded to a host cache and then uploaded to a guest FS location.
+      class BoshIoRelease
+        include VagrantPlugins::VagrantBosh::Errors::UiErrors
+
+        API_URL = "https://bosh.io/api/v1/releases"
+
+        def initialize(name, version, source, checksum, require_checksum, host_cache_dir, guest_root_dir, downloader_opts, release_uploader, ui)
+          @name = name
+          @version = version.to_s
+          @source = source
+          @checksum = ReleaseChecksum.new(checksum) if checksum
+          @require_checksum = require_checksum
+          @host_cache_dir = host_cache_dir
+          @guest_root_dir = guest_root_dir
+          @downloader_opts = downloader_opts
+          @release_uploader = release_uploader
+
+          @ui = ui.for(:deployment, :bosh_io_release)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::bosh_io_release")
+        end
+
+        # Downloads release tarball to host cache; safe to call from multiple
+        # releases concurrently since each release uses its own cache paths.
+        def fetch
+          return if @host_path
+
+          verify_checksum_declared
+
+          resolve_version unless exact_version?
+
+          host_path = File.join(@host_cache_dir, @source, "#{@version}.tgz")
+          @guest_path = File.join(@guest_root_dir, "#{@name}-#{@version}.tgz")
+
+          download(host_path) unless cached?(host_path)
+
+          # Manifest may pin tarball contents in addition to bosh.io's checksum
+          if @checksum && actual = @checksum.mismatch(host_path)
+            raise_error(:checksum_mismatch_error, {
+              name: @name,
+              version: @version,
+              expected: @checksum.to_s,
+              actual: actual,
+            })
+          end
+
+          @host_path = host_path
+        end
+
+        def upload
+          fetch
+
+          @release_uploader.upload(@host_path, @guest_path)
+
+          # Manifest will reference concretely resolved version
+          UploadedReleaseTarball.new(@name, @version, @guest_path)
+        end
+
+        private
+
+        def verify_checksum_declared
+          if @checksum.nil? && @require_checksum
+            raise_error(:missing_checksum_error, name: @name)
+          end
+
+          if @checksum && !@checksum.valid?
+            raise_error(:invalid_checksum_error, name: @name, checksum: @checksum.to_s)
+          end
+        end
+
+        def exact_version?
+          @version != "latest" && Gem::Version.correct?(@version)
+        end
+
+        # Resolves `latest` or constraint (e.g. `~> 255.0`, `>= 1, < 2`)
+        # to the newest matching version published on bosh.io.
+        def resolve_version
+          constraints = @version == "latest" ? [">= 0"] : @version.split(",").map(&:strip)
+
+          begin
+            requirement = Gem::Requirement.new(*constraints)
+          rescue Gem::Requirement::BadRequirementError => e
+            raise_error(:invalid_version_error, name: @name, version: @version, details: e.message)
+          end
+
+          versions = fetch_tarballs.map { |t| t["version"].to_s }
+          versions = versions.select { |v| Gem::Version.correct?(v) }.map { |v| Gem::Version.new(v) }
+
+          resolved = versions.select { |v| requirement.satisfied_by?(v) }.max
+          resolved = resolved.to_s if resolved
+
+          raise_error(:missing_version_error, source: @source, version: @version) unless resolved
+
+          @ui.msg(:resolve_version, name: @name, constraint: @version, version: resolved)
+          @version = resolved
+        end
+
+        # Cached tarball is only trusted if it still matches
+        # checksum that was recorded when it was originally downloaded.
+        def cached?(host_path)
+          sha1_path = "#{host_path}.sha1"
+          return false unless File.file?(host_path) && File.file?(sha1_path)
+
+          recorded = ReleaseChecksum.new(File.read(sha1_path))
+          recorded.valid? && recorded.matches?(host_path)
+        end
+
+        def download(host_path)
+          tarball = find_tarball
+          partial_path = "#{host_path}.part"
+
+          FileUtils.mkdir_p(File.dirname(host_path))
+
+          # Downloads may run concurrently so progress is not shown on a single line.
+          # Partially downloaded tarball is kept around so that
+          # next provision continues download where it left off
+          @ui.msg(:download, name: @name, version: @version)
+          started_at = Time.now
+
+          begin
+            Vagrant::Util::Downloader.new(tarball["url"], partial_path, {continue: true}.merge(@downloader_opts)).download!
+          rescue Vagrant::Errors::DownloaderError => e
+            raise_error(:download_error, url: tarball["url"], details: e.message)
+          end
+
+          # bosh.io reports either plain sha1 or `sha256:<hex>` in sha1 field
+          expected = ReleaseChecksum.new(tarball["sha1"])
+
+          unless expected.valid?
+            raise_error(:invalid_checksum_error, name: @name, checksum: tarball["sha1"].to_s)
+          end
+
+          if actual = expected.mismatch(partial_path)
+            FileUtils.rm_f(partial_path)
+
+            raise_error(:sha1_mismatch_error, {
+              name: @name,
+              version: @version,
+              expected: expected.to_s,
+              actual: actual,
+            })
+          end
+
+          FileUtils.mv(partial_path, host_path)
+          File.write("#{host_path}.sha1", expected.to_s)
+
+          @ui.msg(:downloaded, {
+            name: @name,
+            version: @version,
+            size: "%.1f MB" % (File.size(host_path) / 1024.0 / 1024),
+            duration: "%.2fs" % (Time.now - started_at),
+          })
+        end
+
+        def find_tarball
+          tarball = fetch_tarballs.find { |t| t["version"].to_s == @version }
+          raise_error(:missing_version_error, source: @source, version: @version) unless tarball
+          tarball
+        end
+
+        def fetch_tarballs
+          return @tarballs if @tarballs
+
+          uri = URI.parse("#{API_URL}/#{@source}")
+          @logger.debug("Fetching bosh.io releases from #{uri}")
+
+          begin
+            response = Net::HTTP.get_response(uri)
+          rescue StandardError => e
+            raise_error(:api_error, source: @source, details: e.inspect)
+          end
+
+          unless response.is_a?(Net::HTTPSuccess)
+            raise_error(:api_error, source: @source, details: "HTTP #{response.code}")
+          end
+
+          begin
+            @tarballs = JSON.parse(response.body)
+          rescue JSON::ParserError => e
+            raise_error(:api_error, source: @source, details: e.inspect)
+          end
+        end
+      end
+
+      #~
+    end
+  end
+end
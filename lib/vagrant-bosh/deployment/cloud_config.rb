@@ -0,0 +1,83 @@
+require "log4r"
+require "yaml"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # CloudConfig merges IaaS related sections of a separate cloud config
+      # into the manifest. Entries declared in the manifest take precedence
+      # over identically named cloud config entries.
+      class CloudConfig
+        include VagrantPlugins::VagrantBosh::Errors::UiErrors
+
+        MERGED_SECTIONS = %w(networks compilation)
+
+        # Sections that provisioner does not use once disk types are resolved
+        DROPPED_SECTIONS = %w(azs vm_types vm_extensions disk_types)
+
+        def initialize(cloud_config, ui)
+          @cloud_config = cloud_config
+
+          @ui = ui.for(:deployment, :cloud_config)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::cloud_config")
+        end
+
+        def apply(manifest)
+          MERGED_SECTIONS.each do |section|
+            manifest[section] = merge_section(parsed_cloud_config[section], manifest[section])
+            manifest.delete(section) if manifest[section].nil?
+          end
+
+          disk_types = merge_section(parsed_cloud_config["disk_types"], manifest["disk_types"]) || []
+
+          (manifest["jobs"] || manifest["instance_groups"] || []).each do |job|
+            next unless disk_type_name = job.delete("persistent_disk_type")
+
+            unless disk_type = disk_types.find { |d| d["name"] == disk_type_name }
+              raise_error(:missing_disk_type_error, job: job["name"], disk_type: disk_type_name)
+            end
+
+            job["persistent_disk"] = disk_type["disk_size"]
+          end
+
+          DROPPED_SECTIONS.each { |section| manifest.delete(section) }
+
+          manifest
+        end
+
+        private
+
+        def merge_section(cloud_value, manifest_value)
+          case cloud_value
+          when Array
+            names = (manifest_value || []).map { |entry| entry["name"] }
+            (manifest_value || []) + cloud_value.reject { |entry| names.include?(entry["name"]) }
+          when Hash
+            cloud_value.merge(manifest_value || {})
+          else
+            manifest_value
+          end
+        end
+
+        def parsed_cloud_config
+          return @parsed_cloud_config if @parsed_cloud_config
+
+          begin
+            @parsed_cloud_config = YAML.load(@cloud_config) || {}
+          rescue Psych::SyntaxError => e
+            raise_error(:parse_error, details: e.inspect)
+          end
+
+          unless @parsed_cloud_config.is_a?(Hash)
+            raise_error(:non_hash_class_error, actual_class: @parsed_cloud_config.class.to_s)
+          end
+
+          @parsed_cloud_config
+        end
+      end
+
+      #~
+    end
+  end
+end
@@ -0,0 +1,101 @@
+require "log4r"
+require "json"
+require "uri"
+require "openssl"
+require "net/http"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # CredhubVariables provides variables stored in a CredHub server.
+      # It authenticates with UAA client credentials advertised by CredHub's /info.
+      # Relative variable names are looked up under configured prefix (default: `/`).
+      class CredhubVariables
+        include VagrantPlugins::VagrantBosh::Errors::UiErrors
+
+        def initialize(opts, ui)
+          @url       = opts.fetch("url")
+          @client    = opts.fetch("client")
+          @secret    = opts.fetch("client_secret")
+          @ca_cert   = opts["ca_cert"]
+          @prefix    = opts["prefix"] || "/"
+
+          @ui = ui.for(:deployment, :credhub_variables)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::credhub_variables")
+        end
+
+        def find(name)
+          full_name = name.start_with?("/") ? name : File.join(@prefix, name)
+
+          uri = URI.parse("#{@url}/api/v1/data")
+          uri.query = URI.encode_www_form(name: full_name, current: true)
+
+          response = request(Net::HTTP::Get.new(uri), "Authorization" => "Bearer #{token}")
+
+          return [nil, false] if response.is_a?(Net::HTTPNotFound)
+
+          unless response.is_a?(Net::HTTPSuccess)
+            raise_error(:request_error, name: full_name, details: "HTTP #{response.code}")
+          end
+
+          data = parse_json(response.body, full_name).fetch("data", [])
+          data.empty? ? [nil, false] : [data.first["value"], true]
+        end
+
+        private
+
+        def token
+          return @token if @token
+
+          info = request(Net::HTTP::Get.new(URI.parse("#{@url}/info")), {})
+          unless info.is_a?(Net::HTTPSuccess)
+            raise_error(:auth_error, details: "CredHub /info returned HTTP #{info.code}")
+          end
+
+          auth_url = parse_json(info.body, "/info").fetch("auth-server", {})["url"]
+          raise_error(:auth_error, details: "CredHub /info is missing auth-server.url") unless auth_url
+
+          token_request = Net::HTTP::Post.new(URI.parse("#{auth_url}/oauth/token"))
+          token_request.basic_auth(@client, @secret)
+          token_request.set_form_data("grant_type" => "client_credentials")
+
+          response = request(token_request, "Accept" => "application/json")
+          unless response.is_a?(Net::HTTPSuccess)
+            raise_error(:auth_error, details: "UAA returned HTTP #{response.code}")
+          end
+
+          @token = parse_json(response.body, "/oauth/token")["access_token"]
+        end
+
+        def request(req, headers)
+          headers.each { |k, v| req[k] = v }
+
+          uri = req.uri
+          opts = {use_ssl: uri.scheme == "https", verify_mode: OpenSSL::SSL::VERIFY_PEER}
+
+          if @ca_cert
+            opts[:cert_store] = OpenSSL::X509::Store.new.tap do |store|
+              store.set_default_paths
+              @ca_cert.scan(/-----BEGIN CERTIFICATE-----.+?-----END CERTIFICATE-----/m).each do |pem|
+                store.add_cert(OpenSSL::X509::Certificate.new(pem))
+              end
+            end
+          end
+
+          Net::HTTP.start(uri.host, uri.port, opts) { |http| http.request(req) }
+        rescue StandardError => e
+          raise_error(:request_error, name: uri.to_s, details: e.inspect)
+        end
+
+        def parse_json(body, name)
+          JSON.parse(body)
+        rescue JSON::ParserError => e
+          raise_error(:request_error, name: name, details: e.inspect)
+        end
+      end
+
+      #~
+    end
+  end
+end
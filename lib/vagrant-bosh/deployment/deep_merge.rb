@@ -0,0 +1,16 @@
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      module DeepMerge
+        # Merges nested hashes; values from b take precedence over values from a.
+        def self.merge(a, b)
+          a.merge(b) do |_, a_value, b_value|
+            a_value.is_a?(Hash) && b_value.is_a?(Hash) ? merge(a_value, b_value) : b_value
+          end
+        end
+      end
+
+      #~
+    end
+  end
+end
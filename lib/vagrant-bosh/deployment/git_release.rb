@@ -0,0 +1,64 @@
+require "log4r"
+require "shellwords"
+require "vagrant/util/subprocess"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # GitRelease represents a release repository that is checked out
+      # on the host and then handled as a regular release directory.
+      class GitRelease
+        def initialize(name, url, ref, host_dir, uploadable_release, ui)
+          @name = name
+          @url = url
+          @ref = ref
+          @host_dir = host_dir
+          @uploadable_release = uploadable_release
+
+          @ui = ui.for(:deployment, :git_release)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::git_release")
+        end
+
+        def upload
+          checkout
+          @uploadable_release.upload
+        end
+
+        private
+
+        def checkout
+          url = Shellwords.escape(@url)
+          ref = Shellwords.escape(@ref)
+          dir = Shellwords.escape(@host_dir)
+
+          # Prefer remote branch so that subsequent provisions pick up new commits
+          script = [
+            "set -e",
+            "[ -d #{dir}/.git ] || git clone -q #{url} #{dir}",
+            "cd #{dir}",
+            "git fetch -q origin",
+            "if git rev-parse -q --verify origin/#{ref} >/dev/null; then " +
+              "git checkout -q -f origin/#{ref}; else git checkout -q -f #{ref}; fi",
+            "git submodule -q update --init --recursive",
+          ].join("\n")
+
+          result = @ui.timed_msg(:checkout, name: @name, url: @url, ref: @ref) do
+            Vagrant::Util::Subprocess.execute("bash", "-c", script)
+          end
+
+          if result.exit_code != 0
+            error_msg = @ui.msg_string(:checkout_error, {
+              name: @name,
+              stdout: result.stdout,
+              stderr: result.stderr,
+            })
+            raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+          end
+        end
+      end
+
+      #~
+    end
+  end
+end
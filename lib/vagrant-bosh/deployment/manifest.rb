@@ -5,8 +5,14 @@ module VagrantPlugins
   module VagrantBosh
     module Deployment
       class Manifest
-        def initialize(manifest, uploadable_release_factory, ui)
+        # Transforms respond to `apply(parsed_manifest)` and are applied in order
+        # (e.g. ops files) before releases are resolved.
+        # Uninterpolated manifest is the result of source transforms only
+        # (e.g. ops files) and does not include variable values.
+        def initialize(manifest, source_transforms, transforms, uploadable_release_factory, ui)
           @manifest = manifest
+          @source_transforms = source_transforms
+          @transforms = transforms
           @uploadable_release_factory = uploadable_release_factory
 
           @ui = ui.for(:deployment, :manifest)
@@ -14,13 +20,20 @@ module VagrantPlugins
         end
 
         # Syncs releases to guest FS and rewrites manifest to reference guest FS locations.
+        # Releases downloaded on the host (e.g. bosh.io) are fetched in parallel first.
         def resolve_releases
-          uploaded_releases = uploadable_releases.map(&:upload)
+          releases = uploadable_releases
+
+          @uploadable_release_factory.fetch(releases)
+
+          uploaded_releases = releases.map(&:upload)
 
           parsed_releases.each do |release|
             uploaded_releases.each do |uploaded_release|
               if release["name"] == uploaded_release.name
                 release.merge!(uploaded_release.as_hash)
+                release.delete("git")
+                release.delete("ref")
               end
             end
           end
@@ -32,19 +45,50 @@ module VagrantPlugins
           YAML.dump(parsed_manifest)
         end
 
+        # Safe to keep on the host since secrets are not interpolated
+        def as_uninterpolated_string
+          parsed_manifest
+          @uninterpolated_manifest
+        end
+
         private
 
-        # Returns releases with url matching `dir+bosh://...`
+        # Returns releases with `git` key or url matching `dir+bosh://...`, `bosh.io://...` or `oci://...`
         def uploadable_releases
           parsed_releases.map { |release|
+            if release["git"]
+              next @uploadable_release_factory.new_git_release(
+                release["name"], 
+                release["version"] || "latest", 
+                release["git"], 
+                release["ref"] || "master",
+              )
+            end
+
             next unless url = release["url"]
-            next unless url =~ %r{\Adir\+bosh://(.+)\z}
 
-            @uploadable_release_factory.new_uploadable_release(
-              release["name"], 
-              release["version"], 
-              $1, # host_dir
-            )
+            case url
+            when %r{\Adir\+bosh://(.+)\z}
+              @uploadable_release_factory.new_uploadable_release(
+                release["name"], 
+                release["version"], 
+                $1, # host_dir
+              )
+            when %r{\Abosh\.io://(.+)\z}
+              @uploadable_release_factory.new_bosh_io_release(
+                release["name"], 
+                release["version"], 
+                $1, # source e.g. github.com/cloudfoundry/bosh
+                release["sha1"],
+              )
+            when %r{\Aoci://(.+)\z}
+              @uploadable_release_factory.new_oci_release(
+                release["name"], 
+                release["version"], 
+                $1, # ref e.g. ghcr.io/org/releases/bosh:255.8
+                release["sha1"],
+              )
+            end
           }.compact
         end
 
@@ -69,7 +113,12 @@ module VagrantPlugins
             raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
           end
 
-          @parsed_manifest
+          @parsed_manifest = @source_transforms.inject(@parsed_manifest) { |m, t| t.apply(m) }
+
+          # Remaining transforms modify parsed manifest in place
+          @uninterpolated_manifest = YAML.dump(@parsed_manifest)
+
+          @parsed_manifest = @transforms.inject(@parsed_manifest) { |m, t| t.apply(m) }
         end
       end
 
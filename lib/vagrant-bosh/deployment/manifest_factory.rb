@@ -1,26 +1,79 @@
 require "vagrant-bosh/deployment/manifest"
+require "vagrant-bosh/deployment/manifest_preprocessor"
+require "vagrant-bosh/deployment/ops_file"
+require "vagrant-bosh/deployment/variables"
+require "vagrant-bosh/deployment/vars_store"
+require "vagrant-bosh/deployment/credhub_variables"
+require "vagrant-bosh/deployment/cloud_config"
+require "vagrant-bosh/deployment/strict_manifest"
+require "vagrant-bosh/deployment/v2_manifest"
+require "vagrant-bosh/deployment/addons"
+require "vagrant-bosh/deployment/manifest_validator"
 
 module VagrantPlugins
   module VagrantBosh
     module Deployment
       class ManifestFactory
-        def initialize(uploadable_release_factory, ui)
+        def initialize(uploadable_release_factory, preprocessor, cloud_config, ops_file_paths, vars_file_paths, vars_env, vars, vars_store_path, credhub, strict, ui)
           @uploadable_release_factory = uploadable_release_factory
+          @preprocessor = preprocessor
+          @cloud_config = cloud_config
+          @ops_file_paths = ops_file_paths
+          @vars_file_paths = vars_file_paths
+          @vars_env = vars_env
+          @vars = vars
+          @vars_store_path = vars_store_path
+          @credhub = credhub
+          @strict = strict
           @ui = ui
         end
 
         def new_manifest(manifest)
+          manifest = @preprocessor.process(manifest) if @preprocessor
+
           if manifest.empty?
             EmptyManifest.new
           else
-            Manifest.new(manifest, @uploadable_release_factory, @ui)
+            Manifest.new(manifest, source_transforms, transforms, @uploadable_release_factory, @ui)
           end
         end
+
+        private
+
+        # Ops files are applied before variables are generated and interpolated
+        # so that ops files can introduce new variables and placeholders.
+        # Unknown keys are checked right after ops files, before any other
+        # transform removes or rewrites sections, so that reported paths match what user wrote.
+        def source_transforms
+          ops_files = @ops_file_paths.map { |path| OpsFile.new(path, @ui) }
+          ops_files + [(StrictManifest.new(@strict, @ui) if @strict)].compact
+        end
+
+        # Manifest is validated once values are interpolated but before V2 manifest
+        # is normalized and addons are applied so that reported paths match what user wrote.
+        def transforms
+          # Explicitly provided variables take precedence over CredHub and generated ones
+          provided = [StaticVariables.new(@vars_file_paths, @vars_env, @vars, @ui)]
+          provided << CredhubVariables.new(@credhub, @ui) if @credhub
+
+          vars_store = VarsStore.new(@vars_store_path, provided, @ui)
+          sources = provided + [vars_store]
+
+          [
+            vars_store,
+            CloudConfig.new(@cloud_config || "", @ui),
+            Variables.new(sources, @ui),
+            ManifestValidator.new(@ui),
+            V2Manifest.new(@ui),
+            Addons.new(@ui),
+          ]
+        end
       end
 
       class EmptyManifest
         def resolve_releases; end
         def as_string;    ""; end
+        def as_uninterpolated_string; ""; end
       end
 
       #~
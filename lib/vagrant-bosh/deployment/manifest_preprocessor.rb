@@ -0,0 +1,84 @@
+require "log4r"
+require "erb"
+require "tempfile"
+require "shellwords"
+require "vagrant/util/subprocess"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # Manifest preprocessors respond to `process(manifest_str)`
+      # and are applied to the manifest before it's parsed as YAML.
+      module ManifestPreprocessor
+        def self.for(preprocessor, root_path, ui)
+          case preprocessor.to_s
+          when ""    then nil
+          when "erb" then ErbPreprocessor.new(ui)
+          else CommandPreprocessor.new(preprocessor, root_path, ui)
+          end
+        end
+      end
+
+      # ErbPreprocessor renders manifest as an ERB template on the host.
+      class ErbPreprocessor
+        def initialize(ui)
+          @ui = ui.for(:deployment, :manifest_preprocessor)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::erb_preprocessor")
+        end
+
+        def process(manifest_str)
+          # Template should not see preprocessor's internals
+          ERB.new(manifest_str).result(Object.new.instance_eval { binding })
+        rescue StandardError, SyntaxError => e
+          error_msg = @ui.msg_string(:erb_error, details: e.inspect)
+          raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+        end
+      end
+
+      # CommandPreprocessor runs a command on the host with manifest
+      # on its stdin and uses its stdout as a new manifest.
+      class CommandPreprocessor
+        def initialize(cmd, root_path, ui)
+          @cmd = cmd
+          @root_path = root_path
+
+          @ui = ui.for(:deployment, :manifest_preprocessor)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::command_preprocessor")
+        end
+
+        def process(manifest_str)
+          file = Tempfile.new("manifest-preprocessor")
+          file.write(manifest_str)
+          file.flush
+
+          # Redirect whole script's stdin so that compound commands
+          # (pipelines, `&&`, trailing comments) all read the manifest
+          script = "exec < #{Shellwords.escape(file.path)}\n#{@cmd}"
+
+          result = @ui.timed_msg(:run_cmd, cmd: @cmd) do
+            Vagrant::Util::Subprocess.execute(
+              "bash", "-c", script,
+              {workdir: @root_path.to_s},
+            )
+          end
+
+          if result.exit_code != 0
+            error_msg = @ui.msg_string(:cmd_error, {
+              cmd: @cmd,
+              stdout: result.stdout,
+              stderr: result.stderr,
+            })
+            raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+          end
+
+          result.stdout
+        ensure
+          file.close! if file
+        end
+      end
+
+      #~
+    end
+  end
+end
@@ -0,0 +1,179 @@
+require "log4r"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # ManifestValidator checks required fields, their types and
+      # cross-references between sections (e.g. job templates reference
+      # declared releases) and reports all problems at once.
+      # It runs before V2 manifest is normalized and addons are applied
+      # so that reported paths match what user wrote.
+      class ManifestValidator
+        def initialize(ui)
+          @ui = ui.for(:deployment, :manifest_validator)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::manifest_validator")
+        end
+
+        def apply(manifest)
+          @errors = []
+
+          expect(manifest, "name", String)
+
+          release_names = validate_releases(manifest)
+          network_names = validate_networks(manifest)
+
+          validate_jobs(manifest, release_names, network_names)
+          validate_addons(manifest, release_names)
+
+          expect(manifest, "properties", Hash, optional: true)
+
+          if expect(manifest, "compilation", Hash, optional: true)
+            validate_network_ref(manifest["compilation"], "compilation", network_names)
+          end
+
+          unless @errors.empty?
+            error_msg = @ui.msg_string(:invalid_error, errors: @errors.map { |e| "  - #{e}" }.join("\n"))
+            raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+          end
+
+          manifest
+        end
+
+        private
+
+        def validate_releases(manifest)
+          return [] unless expect(manifest, "releases", Array)
+
+          manifest["releases"].each_with_index.map { |release, i|
+            path = "releases[#{i}]"
+            next unless expect_hash(release, path)
+
+            # Git releases default to latest dev release when version is omitted
+            expect(release, "version", [String, Integer, Float], path: path, optional: release.has_key?("git"))
+            release["name"] if expect(release, "name", String, path: path)
+          }.compact
+        end
+
+        def validate_networks(manifest)
+          return [] unless expect(manifest, "networks", Array)
+
+          manifest["networks"].each_with_index.map { |network, i|
+            path = "networks[#{i}]"
+            next unless expect_hash(network, path)
+
+            expect(network, "type", String, path: path, optional: true)
+            network["name"] if expect(network, "name", String, path: path)
+          }.compact
+        end
+
+        # V2 manifests declare instance groups with jobs instead of jobs with templates
+        def validate_jobs(manifest, release_names, network_names)
+          jobs_key, templates_key =
+            manifest.has_key?("instance_groups") ? %w(instance_groups jobs) : %w(jobs templates)
+
+          return unless expect(manifest, jobs_key, Array)
+
+          manifest[jobs_key].each_with_index do |job, i|
+            path = "#{jobs_key}[#{i}]"
+            next unless expect_hash(job, path)
+
+            expect(job, "name", String, path: path)
+            expect(job, "instances", Integer, path: path, optional: true)
+            expect(job, "properties", Hash, path: path, optional: true)
+
+            validate_templates(job, templates_key, path, release_names)
+
+            if expect(job, "networks", Array, path: path)
+              job["networks"].each_with_index do |network, j|
+                network_path = "#{path}.networks[#{j}]"
+                next unless expect_hash(network, network_path)
+                validate_network_ref(network, network_path, network_names, "name")
+              end
+            end
+          end
+        end
+
+        def validate_addons(manifest, release_names)
+          return unless expect(manifest, "addons", Array, optional: true)
+
+          manifest["addons"].each_with_index do |addon, i|
+            path = "addons[#{i}]"
+            next unless expect_hash(addon, path)
+
+            expect(addon, "name", String, path: path)
+            expect(addon, "properties", Hash, path: path, optional: true)
+            expect(addon, "include", Hash, path: path, optional: true)
+            expect(addon, "exclude", Hash, path: path, optional: true)
+
+            templates_key = addon.has_key?("templates") && !addon.has_key?("jobs") ? "templates" : "jobs"
+            validate_templates(addon, templates_key, path, release_names, optional: true)
+          end
+        end
+
+        def validate_templates(hash, key, path, release_names, opts={})
+          return unless expect(hash, key, Array, path: path, optional: opts[:optional])
+
+          hash[key].each_with_index do |template, j|
+            template_path = "#{path}.#{key}[#{j}]"
+            next unless expect_hash(template, template_path)
+
+            expect(template, "name", String, path: template_path)
+            expect(template, "properties", Hash, path: template_path, optional: true)
+
+            if expect(template, "release", String, path: template_path)
+              unless release_names.include?(template["release"])
+                @errors << "#{template_path}.release: release '#{template["release"]}' is not declared in releases"
+              end
+            end
+          end
+        end
+
+        def validate_network_ref(hash, path, network_names, key="network")
+          return unless expect(hash, key, String, path: path)
+
+          unless network_names.include?(hash[key])
+            @errors << "#{path}.#{key}: network '#{hash[key]}' is not declared in networks"
+          end
+        end
+
+        # Returns true if value at key is present and is of expected type
+        def expect(hash, key, types, opts={})
+          full_path = [opts[:path], key].compact.join(".")
+          types = Array(types)
+
+          unless hash.has_key?(key)
+            @errors << "#{full_path}: must be specified" unless opts[:optional]
+            return false
+          end
+
+          unless types.any? { |t| hash[key].is_a?(t) }
+            @errors << "#{full_path}: must be #{types.map { |t| type_name(t) }.join(" or ")} (was #{type_name(hash[key].class)})"
+            return false
+          end
+
+          true
+        end
+
+        def expect_hash(value, path)
+          return true if value.is_a?(Hash)
+          @errors << "#{path}: must be a hash (was #{type_name(value.class)})"
+          false
+        end
+
+        def type_name(klass)
+          {
+            Hash     => "a hash",
+            Array    => "an array",
+            String   => "a string",
+            Integer  => "an integer",
+            Float    => "a number",
+            NilClass => "empty",
+          }.fetch(klass, klass.to_s)
+        end
+      end
+
+      #~
+    end
+  end
+end
@@ -0,0 +1,252 @@
+require "log4r"
+require "json"
+require "uri"
+require "net/http"
+require "fileutils"
+require "digest/sha2"
+require "vagrant/util/downloader"
+require "vagrant-bosh/errors"
+require "vagrant-bosh/deployment/uploadable_release"
+require "vagrant-bosh/deployment/release_checksum"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # OciRelease represents a release tarball pushed as an OCI artifact
+      # (e.g. via `oras push`) that is downloaded to a host cache
+      # and then uploaded to a guest FS location.
+      class OciRelease
+        include VagrantPlugins::VagrantBosh::Errors::UiErrors
+
+        MANIFEST_MEDIA_TYPES = [
+          "application/vnd.oci.image.manifest.v1+json",
+          "application/vnd.docker.distribution.manifest.v2+json",
+        ]
+
+        def initialize(name, version, ref, checksum, require_checksum, credentials, host_cache_dir, guest_root_dir, downloader_opts, release_uploader, ui)
+          @name = name
+          @version = version.to_s
+          @checksum = ReleaseChecksum.new(checksum) if checksum
+          @require_checksum = require_checksum
+          @downloader_opts = downloader_opts
+          @release_uploader = release_uploader
+
+          # e.g. ghcr.io/org/releases/bosh:255.8; tag defaults to release version
+          @host, @repository = ref.split("/", 2)
+          @repository, @tag = @repository.split(":", 2) if @repository
+          @tag ||= @version
+
+          @credentials = credentials[@host] || {}
+
+          @ui = ui.for(:deployment, :oci_release)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::oci_release")
+
+          @host_cache_dir = File.join(host_cache_dir, "oci")
+          @guest_path = File.join(guest_root_dir, "#{name}-#{@version}.tgz")
+        end
+
+        # Downloads release tarball to host cache; see BoshIoRelease#fetch.
+        def fetch
+          return if @host_path
+
+          raise_error(:invalid_ref_error, name: @name) unless @host && @repository
+
+          # Release tarball is referenced as file:// so version cannot be resolved by director
+          raise_error(:invalid_version_error, name: @name, version: @version) unless exact_version?
+
+          if @checksum.nil? && @require_checksum
+            raise_error(:missing_checksum_error, name: @name)
+          end
+
+          if @checksum && !@checksum.valid?
+            raise_error(:invalid_checksum_error, name: @name, checksum: @checksum.to_s)
+          end
+
+          unless digest = cached_digest
+            digest = find_layer_digest
+            download(digest, blob_path(digest)) unless File.file?(blob_path(digest))
+            record_digest(digest)
+          end
+
+          host_path = blob_path(digest)
+
+          if @checksum && actual = @checksum.mismatch(host_path)
+            raise_error(:checksum_mismatch_error, {
+              name: @name,
+              ref: ref,
+              expected: @checksum.to_s,
+              actual: actual,
+            })
+          end
+
+          @host_path = host_path
+        end
+
+        def upload
+          fetch
+
+          @release_uploader.upload(@host_path, @guest_path)
+
+          UploadedReleaseTarball.new(@name, @version, @guest_path)
+        end
+
+        private
+
+        def exact_version?
+          @version != "latest" && @version =~ /\A[0-9A-Za-z][0-9A-Za-z.+_-]*\z/
+        end
+
+        # Tags that match release version are treated as immutable, so
+        # registry is not contacted again once their tarball is cached.
+        def cached_digest
+          return unless @tag == @version && File.file?(digest_path)
+
+          digest = File.read(digest_path).strip
+          algorithm, expected = digest.split(":", 2)
+
+          return unless algorithm == "sha256" && File.file?(blob_path(digest))
+          digest if Digest::SHA256.file(blob_path(digest)).hexdigest == expected
+        end
+
+        def record_digest(digest)
+          FileUtils.mkdir_p(File.dirname(digest_path))
+          File.write(digest_path, digest)
+        end
+
+        def blob_path(digest)
+          File.join(@host_cache_dir, "#{digest.sub(":", "-")}.tgz")
+        end
+
+        def digest_path
+          File.join(@host_cache_dir, "refs", @host, @repository, "#{@tag}.digest")
+        end
+
+        # Release tarball is expected to be the only layer of the artifact.
+        def find_layer_digest
+          response = registry_get("manifests/#{@tag}", "Accept" => MANIFEST_MEDIA_TYPES.join(", "))
+
+          begin
+            layers = JSON.parse(response.body)["layers"] || []
+          rescue JSON::ParserError => e
+            raise_error(:registry_error, ref: ref, details: e.inspect)
+          end
+
+          if layers.size != 1
+            raise_error(:layers_error, ref: ref, count: layers.size)
+          end
+
+          layers.first["digest"]
+        end
+
+        def download(digest, host_path)
+          algorithm, expected = digest.split(":", 2)
+          raise_error(:digest_error, ref: ref, digest: digest) unless algorithm == "sha256"
+
+          partial_path = "#{host_path}.part"
+          FileUtils.mkdir_p(File.dirname(host_path))
+
+          # Registries usually redirect blob requests to a storage backend
+          @ui.msg(:download, name: @name, ref: ref)
+          started_at = Time.now
+
+          begin
+            Vagrant::Util::Downloader.new(
+              registry_uri("blobs/#{digest}").to_s,
+              partial_path,
+              {
+                continue: true,
+                headers: auth_headers.map { |k, v| "#{k}: #{v}" },
+              }.merge(@downloader_opts),
+            ).download!
+          rescue Vagrant::Errors::DownloaderError => e
+            raise_error(:registry_error, ref: ref, details: e.message)
+          end
+
+          actual = Digest::SHA256.file(partial_path).hexdigest
+
+          if actual != expected
+            FileUtils.rm_f(partial_path)
+            raise_error(:sha256_mismatch_error, ref: ref, expected: expected, actual: actual)
+          end
+
+          FileUtils.mv(partial_path, host_path)
+
+          @ui.msg(:downloaded, {
+            name: @name,
+            ref: ref,
+            size: "%.1f MB" % (File.size(host_path) / 1024.0 / 1024),
+            duration: "%.2fs" % (Time.now - started_at),
+          })
+        end
+
+        def registry_get(path, headers={})
+          response = http_get(registry_uri(path), headers.merge(auth_headers))
+
+          # Registry tells which token service to use via challenge
+          if response.is_a?(Net::HTTPUnauthorized) && @auth_headers.nil?
+            @auth_headers = authenticate(response["WWW-Authenticate"].to_s)
+            response = http_get(registry_uri(path), headers.merge(auth_headers))
+          end
+
+          unless response.is_a?(Net::HTTPSuccess)
+            raise_error(:registry_error, ref: ref, details: "HTTP #{response.code}")
+          end
+
+          response
+        end
+
+        def authenticate(challenge)
+          unless challenge =~ /\ABearer (.+)\z/i
+            return basic_auth_headers
+          end
+
+          params = Hash[$1.scan(/(\w+)="([^"]*)"/)]
+          uri = URI.parse(params.fetch("realm", ""))
+          uri.query = URI.encode_www_form(params.reject { |k, _| k == "realm" })
+
+          response = http_get(uri, basic_auth_headers)
+
+          unless response.is_a?(Net::HTTPSuccess)
+            raise_error(:registry_auth_error, ref: ref, details: "HTTP #{response.code}")
+          end
+
+          token = JSON.parse(response.body).values_at("token", "access_token").compact.first
+          {"Authorization" => "Bearer #{token}"}
+        rescue JSON::ParserError, URI::InvalidURIError => e
+          raise_error(:registry_auth_error, ref: ref, details: e.inspect)
+        end
+
+        def auth_headers
+          @auth_headers || {}
+        end
+
+        def basic_auth_headers
+          return {} unless @credentials["username"]
+          encoded = ["#{@credentials["username"]}:#{@credentials["password"]}"].pack("m0")
+          {"Authorization" => "Basic #{encoded}"}
+        end
+
+        def http_get(uri, headers)
+          request = Net::HTTP::Get.new(uri)
+          headers.each { |k, v| request[k] = v }
+
+          Net::HTTP.start(uri.host, uri.port, use_ssl: uri.scheme == "https") do |http|
+            http.request(request)
+          end
+        rescue StandardError => e
+          raise_error(:registry_error, ref: ref, details: e.inspect)
+        end
+
+        def registry_uri(path)
+          URI.parse("https://#{@host}/v2/#{@repository}/#{path}")
+        end
+
+        def ref
+          "#{@host}/#{@repository}:#{@tag}"
+        end
+      end
+
+      #~
+    end
+  end
+end
@@ -0,0 +1,295 @@
+require "log4r"
+require "yaml"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # OpsFile applies go-patch style operations (replace, remove)
+      # from a file located on the host FS to a parsed manifest.
+      class OpsFile
+        include VagrantPlugins::VagrantBosh::Errors::UiErrors
+
+        class Error < StandardError; end
+
+        def initialize(path, ui)
+          @path = path
+
+          @ui = ui.for(:deployment, :ops_file)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::ops_file")
+        end
+
+        def apply(manifest)
+          ops.each_with_index.inject(manifest) do |result, (op, i)|
+            begin
+              apply_op(result, op)
+            rescue Error => e
+              raise_error(:apply_error, path: @path, index: i, details: e.message)
+            end
+          end
+        end
+
+        private
+
+        def ops
+          begin
+            ops = YAML.load(File.read(@path))
+          rescue SystemCallError, Psych::SyntaxError => e
+            raise_error(:parse_error, path: @path, details: e.inspect)
+          end
+
+          unless ops.is_a?(Array)
+            raise_error(:non_array_class_error, path: @path, actual_class: ops.class.to_s)
+          end
+
+          ops
+        end
+
+        def apply_op(manifest, op)
+          raise Error, "Expected operation to be a Hash" unless op.is_a?(Hash)
+
+          tokens = Path.parse(op["path"])
+
+          case op["type"]
+          when "replace"
+            raise Error, "Missing value" unless op.has_key?("value")
+            Replace.new(tokens, deep_copy(op["value"])).apply(manifest)
+          when "remove"
+            Remove.new(tokens).apply(manifest)
+          else
+            raise Error, "Unknown operation type '#{op["type"]}'"
+          end
+        end
+
+        def deep_copy(obj)
+          Marshal.load(Marshal.dump(obj))
+        end
+
+        # Path parses go-patch pointers, e.g. `/jobs/name=bosh/templates/-`.
+        # Token that ends with `?` makes it and all subsequent tokens optional.
+        module Path
+          KeyToken      = Struct.new(:key, :optional)
+          IndexToken    = Struct.new(:index, :modifier)
+          AfterLast     = Class.new
+          MatchingToken = Struct.new(:key, :value, :optional, :modifier)
+
+          def self.parse(path)
+            unless path.is_a?(String) && path.start_with?("/")
+              raise Error, "Expected path '#{path}' to start with '/'"
+            end
+
+            return [] if path == "/"
+
+            optional = false
+
+            path.split("/", -1).drop(1).map do |raw|
+              raw = raw.gsub("~1", "/").gsub("~0", "~")
+              raw, modifier = raw.split(/:(before|after)\z/, 2) if raw =~ /:(before|after)\z/
+
+              if raw.end_with?("?")
+                raw = raw[0..-2]
+                optional = true
+              end
+
+              case raw
+              when "-"
+                AfterLast.new
+              when /\A-?\d+\z/
+                IndexToken.new(raw.to_i, modifier)
+              when /\A([^=]+)=(.*)\z/
+                MatchingToken.new($1, $2, optional, modifier)
+              else
+                KeyToken.new(raw, optional)
+              end
+            end
+          end
+        end
+
+        class Replace
+          def initialize(tokens, value)
+            @tokens = tokens
+            @value = value
+          end
+
+          def apply(obj)
+            return @value if @tokens.empty?
+
+            parent = @tokens[0..-2].each_with_index.inject(obj) do |current, (token, i)|
+              descend(current, token, @tokens[i+1])
+            end
+
+            assign(parent, @tokens.last)
+
+            obj
+          end
+
+          private
+
+          def descend(current, token, next_token)
+            case token
+            when Path::KeyToken
+              expect(current, Hash, token)
+
+              unless current.has_key?(token.key)
+                raise Error, "Expected to find map key '#{token.key}'" unless token.optional
+                current[token.key] = container_for(next_token)
+              end
+
+              current[token.key]
+            when Path::IndexToken
+              expect(current, Array, token)
+              current.fetch(token.index) { raise Error, "Expected to find array index #{token.index}" }
+            when Path::AfterLast
+              expect(current, Array, token)
+              container_for(next_token).tap { |c| current << c }
+            when Path::MatchingToken
+              expect(current, Array, token)
+
+              found = current.select { |el| el.is_a?(Hash) && el[token.key].to_s == token.value }
+              raise Error, "Expected to find exactly one array item matching '#{token.key}=#{token.value}'" if found.size > 1
+
+              if found.empty?
+                raise Error, "Expected to find array item matching '#{token.key}=#{token.value}'" unless token.optional
+                found = [{token.key => token.value}]
+                current << found.first
+              end
+
+              found.first
+            end
+          end
+
+          def assign(current, token)
+            case token
+            when Path::KeyToken
+              expect(current, Hash, token)
+
+              if !current.has_key?(token.key) && !token.optional
+                raise Error, "Expected to find map key '#{token.key}'"
+              end
+
+              current[token.key] = @value
+            when Path::IndexToken
+              expect(current, Array, token)
+              index = token.index < 0 ? current.size + token.index : token.index
+              raise Error, "Expected to find array index #{token.index}" unless (0...current.size).include?(index)
+              insert_at(current, index, token.modifier)
+            when Path::AfterLast
+              expect(current, Array, token)
+              current << @value
+            when Path::MatchingToken
+              expect(current, Array, token)
+
+              indexes = current.each_index.select do |i|
+                current[i].is_a?(Hash) && current[i][token.key].to_s == token.value
+              end
+
+              case indexes.size
+              when 1
+                insert_at(current, indexes.first, token.modifier)
+              when 0
+                raise Error, "Expected to find array item matching '#{token.key}=#{token.value}'" unless token.optional
+                current << @value
+              else
+                raise Error, "Expected to find exactly one array item matching '#{token.key}=#{token.value}'"
+              end
+            end
+          end
+
+          def insert_at(array, index, modifier)
+            case modifier
+            when "before" then array.insert(index, @value)
+            when "after"  then array.insert(index + 1, @value)
+            else array[index] = @value
+            end
+          end
+
+          def container_for(next_token)
+            case next_token
+            when Path::AfterLast, Path::IndexToken, Path::MatchingToken then []
+            else {}
+            end
+          end
+
+          def expect(current, klass, token)
+            return if current.is_a?(klass)
+            raise Error, "Expected to find #{klass.name.downcase} at '#{describe(token)}' (was #{current.class})"
+          end
+
+          def describe(token)
+            case token
+            when Path::KeyToken      then token.key
+            when Path::IndexToken    then token.index.to_s
+            when Path::AfterLast     then "-"
+            when Path::MatchingToken then "#{token.key}=#{token.value}"
+            end
+          end
+        end
+
+        class Remove
+          def initialize(tokens)
+            @tokens = tokens
+          end
+
+          def apply(obj)
+            raise Error, "Cannot remove entire document" if @tokens.empty?
+
+            parent = @tokens[0..-2].inject(obj) do |current, token|
+              return obj if current.nil? # optional path is missing
+              find(current, token)
+            end
+
+            delete(parent, @tokens.last) unless parent.nil?
+
+            obj
+          end
+
+          private
+
+          def find(current, token)
+            case token
+            when Path::KeyToken
+              raise Error, "Expected to find a map at '#{token.key}'" unless current.is_a?(Hash)
+              return current[token.key] if current.has_key?(token.key)
+              raise Error, "Expected to find map key '#{token.key}'" unless token.optional
+            when Path::IndexToken
+              raise Error, "Expected to find an array at index #{token.index}" unless current.is_a?(Array)
+              current.fetch(token.index) { raise Error, "Expected to find array index #{token.index}" }
+            when Path::AfterLast
+              raise Error, "Cannot remove after last array item"
+            when Path::MatchingToken
+              raise Error, "Expected to find an array at '#{token.key}=#{token.value}'" unless current.is_a?(Array)
+              found = current.select { |el| el.is_a?(Hash) && el[token.key].to_s == token.value }
+
+              case found.size
+              when 1
+                found.first
+              when 0
+                raise Error, "Expected to find array item matching '#{token.key}=#{token.value}'" unless token.optional
+              else
+                raise Error, "Expected to find exactly one array item matching '#{token.key}=#{token.value}'"
+              end
+            end
+          end
+
+          def delete(current, token)
+            case token
+            when Path::KeyToken
+              find(current, token)
+              current.delete(token.key)
+            when Path::IndexToken
+              find(current, token)
+              current.delete_at(token.index)
+            when Path::AfterLast
+              find(current, token)
+            when Path::MatchingToken
+              found = find(current, token)
+              current.delete_if { |el| el.equal?(found) } if found
+            end
+          end
+        end
+      end
+
+      #~
+    end
+  end
+end
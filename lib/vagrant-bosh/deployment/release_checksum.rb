@@ -0,0 +1,44 @@
+require "digest/sha1"
+require "digest/sha2"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # ReleaseChecksum represents release tarball checksum
+      # in BOSH notation: `sha256:<hex>`, `sha1:<hex>` or plain sha1 `<hex>`.
+      class ReleaseChecksum
+        DIGESTS = {
+          "sha1"   => Digest::SHA1,
+          "sha256" => Digest::SHA256,
+        }
+
+        attr_reader :algorithm, :value
+
+        def initialize(checksum)
+          @algorithm, @value = checksum.to_s.strip.downcase.split(":", 2)
+          @algorithm, @value = "sha1", @algorithm unless @value
+        end
+
+        def valid?
+          DIGESTS.has_key?(@algorithm) && @value =~ /\A\h+\z/
+        end
+
+        def matches?(path)
+          mismatch(path).nil?
+        end
+
+        # Returns actual checksum in same notation if file does not match
+        def mismatch(path)
+          actual = DIGESTS.fetch(@algorithm).file(path).hexdigest
+          "#{@algorithm}:#{actual}" if actual != @value
+        end
+
+        def to_s
+          "#{@algorithm}:#{@value}"
+        end
+      end
+
+      #~
+    end
+  end
+end
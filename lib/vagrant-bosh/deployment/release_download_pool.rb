@@ -0,0 +1,71 @@
+require "log4r"
+require "thread"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # ReleaseDownloadPool fetches releases that are downloaded on the host
+      # in parallel, running at most `size` downloads at a time.
+      # Overall bandwidth limit (e.g. `2M` bytes per second) is split
+      # evenly between parallel downloads.
+      class ReleaseDownloadPool
+        RATE_UNITS = {"" => 1, "k" => 1024, "m" => 1024**2, "g" => 1024**3}
+
+        def initialize(size, limit_rate, ui)
+          @size = [size.to_i, 1].max
+          @limit_rate = limit_rate
+
+          @ui = ui.for(:deployment, :release_download_pool)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::release_download_pool")
+        end
+
+        # Releases respond to `fetch`. First failure stops picking up
+        # remaining releases and is re-raised once running downloads finish.
+        def fetch(releases)
+          queue = Queue.new
+          releases.each { |r| queue << r }
+
+          failure = nil
+
+          threads = Array.new([@size, releases.size].min) do
+            Thread.new do
+              until failure || queue.empty?
+                begin
+                  release = queue.pop(true)
+                rescue ThreadError
+                  break
+                end
+
+                begin
+                  release.fetch
+                rescue Exception => e
+                  failure ||= e
+                end
+              end
+            end
+          end
+
+          threads.each(&:join)
+
+          raise failure if failure
+        end
+
+        # Options for Vagrant::Util::Downloader
+        def downloader_options
+          return {} unless @limit_rate
+
+          # Passed through to curl as is
+          {box_extra_download_options: ["--limit-rate", (self.class.parse_rate(@limit_rate) / @size).to_s]}
+        end
+
+        # Returns bytes per second for rates such as `500k`, `2M` or `1048576`
+        def self.parse_rate(rate)
+          return unless rate.to_s =~ /\A(\d+)([kmg]?)\z/i
+          $1.to_i * RATE_UNITS[$2.downcase]
+        end
+      end
+
+      #~
+    end
+  end
+end
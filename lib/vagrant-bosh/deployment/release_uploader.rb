@@ -6,8 +6,9 @@ module VagrantPlugins
   module VagrantBosh
     module Deployment
       class ReleaseUploader
-        def initialize(machine, ui)
+        def initialize(machine, asset_uploader, ui)
           @machine = machine
+          @asset_uploader = asset_uploader
 
           @ui = ui.for(:deployment, :release_uploader)
           @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::release_uploader")
@@ -34,6 +35,10 @@ module VagrantPlugins
             })
           end
         end
+
+        def upload(host_path, guest_path)
+          @asset_uploader.upload_file(host_path, guest_path)
+        end
       end
 
       #~
@@ -0,0 +1,113 @@
+require "log4r"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # StrictManifest reports manifest keys that are not recognized
+      # (e.g. `instance` instead of `instances`) either as warnings or as an error.
+      class StrictManifest
+        # Schema values: nil for free-form values, Hash for nested
+        # known keys, and one element Array for arrays of such hashes.
+        JOB_SCHEMA = {
+          "name" => nil, "release" => nil, "properties" => nil,
+          "consumes" => nil, "provides" => nil, "custom_provider_definitions" => nil,
+        }
+
+        NETWORK_SCHEMA = {
+          "name" => nil, "type" => nil, "dns" => nil, "cloud_properties" => nil,
+          "subnets" => [{
+            "range" => nil, "gateway" => nil, "dns" => nil, "static" => nil,
+            "reserved" => nil, "cloud_properties" => nil, "az" => nil, "azs" => nil,
+          }],
+        }
+
+        INSTANCE_GROUP_SCHEMA = {
+          "name" => nil, "instances" => nil, "lifecycle" => nil, "properties" => nil,
+          "resource_pool" => nil, "vm_type" => nil, "vm_extensions" => nil, "stemcell" => nil,
+          "persistent_disk" => nil, "persistent_disk_pool" => nil, "persistent_disk_type" => nil,
+          "update" => nil, "env" => nil, "azs" => nil, "migrated_from" => nil,
+          "templates" => [JOB_SCHEMA],
+          "jobs" => [JOB_SCHEMA],
+          "networks" => [{"name" => nil, "static_ips" => nil, "default" => nil}],
+        }
+
+        SCHEMA = {
+          "name" => nil, "director_uuid" => nil, "properties" => nil,
+          "features" => nil, "tags" => nil, "resource_pools" => nil, "disk_pools" => nil,
+          "azs" => nil, "vm_types" => nil, "vm_extensions" => nil, "disk_types" => nil,
+          "releases" => [{
+            "name" => nil, "version" => nil, "url" => nil, "sha1" => nil,
+            "git" => nil, "ref" => nil, "stemcell" => nil,
+          }],
+          "stemcells" => [{"alias" => nil, "os" => nil, "name" => nil, "version" => nil}],
+          "networks" => [NETWORK_SCHEMA],
+          "compilation" => {
+            "network" => nil, "workers" => nil, "cloud_properties" => nil, "reuse_compilation_vms" => nil,
+            "az" => nil, "vm_type" => nil, "vm_extensions" => nil, "env" => nil,
+          },
+          "update" => {
+            "canaries" => nil, "canary_watch_time" => nil, "update_watch_time" => nil,
+            "max_in_flight" => nil, "serial" => nil,
+          },
+          "jobs" => [INSTANCE_GROUP_SCHEMA],
+          "instance_groups" => [INSTANCE_GROUP_SCHEMA],
+          "variables" => [{
+            "name" => nil, "type" => nil, "options" => nil, "update_mode" => nil, "consumes" => nil,
+          }],
+          "addons" => [{
+            "name" => nil, "properties" => nil, "include" => nil, "exclude" => nil,
+            "jobs" => [JOB_SCHEMA],
+            "templates" => [JOB_SCHEMA],
+          }],
+        }
+
+        # Mode is either `warn` or `error`
+        def initialize(mode, ui)
+          @mode = mode.to_s
+
+          @ui = ui.for(:deployment, :strict_manifest)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::strict_manifest")
+        end
+
+        def apply(manifest)
+          unknown = []
+          check(manifest, SCHEMA, "", unknown)
+
+          if @mode == "error" && !unknown.empty?
+            error_msg = @ui.msg_string(:unknown_keys_error, paths: unknown.map { |p| "  - #{p}" }.join("\n"))
+            raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+          end
+
+          unknown.each { |path| @ui.msg(:unknown_key_warning, path: path) }
+
+          manifest
+        end
+
+        private
+
+        def check(value, schema, path, unknown)
+          case schema
+          when Hash
+            return unless value.is_a?(Hash)
+
+            value.each do |k, v|
+              child_path = path.empty? ? k.to_s : "#{path}.#{k}"
+
+              if schema.has_key?(k)
+                check(v, schema[k], child_path, unknown)
+              else
+                unknown << child_path
+              end
+            end
+          when Array
+            return unless value.is_a?(Array)
+            value.each_with_index { |v, i| check(v, schema.first, "#{path}[#{i}]", unknown) }
+          end
+        end
+      end
+
+      #~
+    end
+  end
+end
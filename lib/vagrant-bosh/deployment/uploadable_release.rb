@@ -1,4 +1,7 @@
 require "log4r"
+require "json"
+require "fileutils"
+require "digest/sha1"
 require "vagrant/util/subprocess"
 require "vagrant-bosh/errors"
 
@@ -10,6 +13,9 @@ module VagrantPlugins
       # UplodableRelease represents a release 
       # that *can be* synced to a guest FS location.
       class UplodableRelease
+        # Directories that affect contents of a created dev release.
+        FINGERPRINTED_DIRS = %w(config jobs packages src)
+
         def initialize(name, version, host_dir, guest_root_dir, release_uploader, create_release_cmd, ui)
           @name = name
           @version = version
@@ -24,7 +30,7 @@ module VagrantPlugins
         end
 
         def upload
-          version = @version == "latest" ? create_release : @version
+          version = @version == "latest" ? latest_release : @version
 
           # Sync either existing or newly-created release
           @release_uploader.sync(@host_dir, @guest_dir)
@@ -34,6 +40,60 @@ module VagrantPlugins
 
         private
 
+        # Reuses previously created dev release unless release files changed since then.
+        def latest_release
+          fingerprint = release_fingerprint
+          state = read_state
+
+          if state["fingerprint"] == fingerprint && dev_release_exists?(state["version"])
+            @ui.msg(:reuse_release, name: @name, version: state["version"])
+            return state["version"]
+          end
+
+          create_release.tap do |version|
+            write_state("fingerprint" => fingerprint, "version" => version)
+          end
+        end
+
+        # Uses file paths, sizes and mtimes since hashing contents of src/ is too slow.
+        def release_fingerprint
+          digest = Digest::SHA1.new
+
+          FINGERPRINTED_DIRS.each do |dir_name|
+            pattern = File.join(@host_dir, dir_name, "**", "*")
+
+            Dir.glob(pattern, File::FNM_DOTMATCH).sort.each do |path|
+              next unless File.file?(path)
+              stat = File.stat(path)
+              digest << "#{path}\0#{stat.size}\0#{stat.mtime.to_i}\n"
+            end
+          end
+
+          digest.hexdigest
+        end
+
+        def dev_release_exists?(version)
+          return false unless version
+          pattern = File.join(@host_dir, "dev_releases", "**", "#{@name}-#{version}.yml")
+          Dir.glob(pattern).any?
+        end
+
+        def read_state
+          JSON.parse(File.read(state_path))
+        rescue Errno::ENOENT, JSON::ParserError
+          {}
+        end
+
+        def write_state(state)
+          FileUtils.mkdir_p(File.dirname(state_path))
+          File.write(state_path, JSON.dump(state))
+        end
+
+        # Kept next to dev builds since it's specific to this release directory.
+        def state_path
+          File.join(@host_dir, ".dev_builds", "vagrant-bosh.json")
+        end
+
         def create_release
           result = @ui.timed_msg(:create_release, name: @name) do
             shell = ENV["SHELL"] || "bash"
@@ -79,6 +139,14 @@ module VagrantPlugins
         end
       end
 
+      # UploadedReleaseTarball represents a release tarball
+      # that *was* uploaded to a guest FS location.
+      class UploadedReleaseTarball < Struct.new(:name, :version, :guest_path)
+        def as_hash
+          {"name" => name, "version" => version, "url" => "file://#{guest_path}"}
+        end
+      end
+
       #~
     end
   end
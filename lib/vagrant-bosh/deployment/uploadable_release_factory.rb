@@ -1,16 +1,29 @@
+require "digest/sha1"
 require "vagrant-bosh/deployment/uploadable_release"
+require "vagrant-bosh/deployment/bosh_io_release"
+require "vagrant-bosh/deployment/git_release"
+require "vagrant-bosh/deployment/oci_release"
 
 module VagrantPlugins
   module VagrantBosh
     module Deployment
       class UploadableReleaseFactory
-        def initialize(guest_root_dir, release_uploader, create_release_cmd, ui)
+        def initialize(guest_root_dir, host_cache_dir, release_uploader, download_pool, create_release_cmd, require_checksums, oci_credentials, ui)
           @guest_root_dir = guest_root_dir
+          @host_cache_dir = host_cache_dir
           @release_uploader = release_uploader
+          @download_pool = download_pool
           @create_release_cmd = create_release_cmd
+          @require_checksums = require_checksums
+          @oci_credentials = oci_credentials
           @ui = ui
         end
 
+        # Downloads releases that are fetched on the host (respond to `fetch`) in parallel
+        def fetch(releases)
+          @download_pool.fetch(releases.select { |r| r.respond_to?(:fetch) })
+        end
+
         def new_uploadable_release(name, version, host_dir)
           UplodableRelease.new(
             name,
@@ -22,6 +35,51 @@ module VagrantPlugins
             @ui,
           )
         end
+
+        def new_bosh_io_release(name, version, source, checksum)
+          BoshIoRelease.new(
+            name,
+            version,
+            source,
+            checksum,
+            @require_checksums,
+            @host_cache_dir,
+            @guest_root_dir,
+            @download_pool.downloader_options,
+            @release_uploader,
+            @ui,
+          )
+        end
+
+        def new_oci_release(name, version, ref, checksum)
+          OciRelease.new(
+            name,
+            version,
+            ref,
+            checksum,
+            @require_checksums,
+            @oci_credentials,
+            @host_cache_dir,
+            @guest_root_dir,
+            @download_pool.downloader_options,
+            @release_uploader,
+            @ui,
+          )
+        end
+
+        def new_git_release(name, version, url, ref)
+          # Checkout is keyed by url so that changing it does not reuse old origin
+          host_dir = File.join(@host_cache_dir, "git", "#{name}-#{Digest::SHA1.hexdigest(url)[0, 10]}")
+
+          GitRelease.new(
+            name,
+            url,
+            ref,
+            host_dir,
+            new_uploadable_release(name, version, host_dir),
+            @ui,
+          )
+        end
       end
 
       #~
@@ -0,0 +1,54 @@
+require "log4r"
+require "vagrant-bosh/deployment/deep_merge"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # V2Manifest normalizes V2 deployment manifests (`instance_groups`,
+      # `stemcells`) into V1 form (`jobs` with `templates`) that provisioner understands.
+      # Manifests without `instance_groups` are left untouched.
+      class V2Manifest
+        # Instance group keys that only make sense with a real director & IaaS
+        IGNORED_INSTANCE_GROUP_KEYS = %w(azs vm_type vm_extensions stemcell migrated_from)
+
+        def initialize(ui)
+          @ui = ui.for(:deployment, :v2_manifest)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::v2_manifest")
+        end
+
+        def apply(manifest)
+          return manifest unless manifest.has_key?("instance_groups")
+
+          @ui.debug_msg(:normalize, name: manifest["name"])
+
+          manifest.delete("stemcells")
+
+          instance_groups = manifest.delete("instance_groups") || []
+          manifest["jobs"] = instance_groups.map { |group| normalize_instance_group(group) }
+
+          manifest
+        end
+
+        private
+
+        # Since provisioner does not scope properties to individual jobs,
+        # job properties are merged into instance group properties.
+        def normalize_instance_group(group)
+          job = group.reject { |k, _| IGNORED_INSTANCE_GROUP_KEYS.include?(k) || k == "jobs" }
+
+          job["templates"] = (group["jobs"] || []).map do |template|
+            if template["properties"]
+              job["properties"] = DeepMerge.merge(job["properties"] || {}, template["properties"])
+            end
+
+            template.reject { |k, _| k == "properties" }
+          end
+
+          job
+        end
+      end
+
+      #~
+    end
+  end
+end
@@ -0,0 +1,157 @@
+require "log4r"
+require "yaml"
+require "vagrant-bosh/errors"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # Variables interpolates `((name))` and `((name.key))` placeholders
+      # in a parsed manifest using values found in variable sources.
+      # Sources respond to `find(name)` and return `[value, found]`;
+      # first source that finds a variable wins.
+      class Variables
+        PLACEHOLDER_REGEX = /\(\(!?([-\/\.\w]+)\)\)/
+
+        def initialize(sources, ui)
+          @sources = sources
+
+          @ui = ui.for(:deployment, :variables)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::variables")
+        end
+
+        def apply(manifest)
+          missing = []
+
+          result = interpolate(manifest, missing)
+
+          unless missing.empty?
+            error_msg = @ui.msg_string(:missing_error, names: missing.uniq.sort.join(", "))
+            raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+          end
+
+          result
+        end
+
+        private
+
+        def interpolate(obj, missing)
+          case obj
+          when Hash
+            Hash[obj.map { |k, v| [interpolate(k, missing), interpolate(v, missing)] }]
+          when Array
+            obj.map { |v| interpolate(v, missing) }
+          when String
+            interpolate_string(obj, missing)
+          else
+            obj
+          end
+        end
+
+        # Placeholder that makes up the whole string is replaced with
+        # a value of any type; otherwise value is embedded as a string.
+        def interpolate_string(str, missing)
+          if str =~ /\A#{PLACEHOLDER_REGEX}\z/
+            name = $1
+            value, found = lookup(name)
+            missing << name unless found
+            return found ? value : str
+          end
+
+          str.gsub(PLACEHOLDER_REGEX) do |placeholder|
+            name = $1
+            value, found = lookup(name)
+            missing << name unless found
+            found ? value.to_s : placeholder
+          end
+        end
+
+        def lookup(full_name)
+          name, *keys = full_name.split(".")
+
+          value, found = find(name)
+          return [nil, false] unless found
+
+          keys.each do |key|
+            return [nil, false] unless value.is_a?(Hash) && value.has_key?(key)
+            value = value[key]
+          end
+
+          [value, true]
+        end
+
+        def find(name)
+          @sources.each do |source|
+            value, found = source.find(name)
+            return [value, true] if found
+          end
+
+          [nil, false]
+        end
+      end
+
+      # StaticVariables provides variables from vars files, environment
+      # (variables prefixed with `<prefix>_`) and explicitly given vars,
+      # in the increasing order of precedence.
+      class StaticVariables
+        def initialize(vars_file_paths, env_prefix, vars, ui)
+          @vars_file_paths = vars_file_paths
+          @env_prefix = env_prefix
+          @vars = vars
+
+          @ui = ui.for(:deployment, :variables)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::static_variables")
+        end
+
+        def find(name)
+          all_vars.has_key?(name) ? [all_vars[name], true] : [nil, false]
+        end
+
+        private
+
+        def all_vars
+          @all_vars ||= {}.tap do |all|
+            @vars_file_paths.each { |path| all.merge!(vars_file(path)) }
+            all.merge!(env_vars)
+            @vars.each { |k, v| all[k.to_s] = v }
+          end
+        end
+
+        def vars_file(path)
+          begin
+            vars = YAML.load(File.read(path))
+          rescue SystemCallError, Psych::SyntaxError => e
+            error_msg = @ui.msg_string(:vars_file_parse_error, path: path, details: e.inspect)
+            raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+          end
+
+          unless vars.is_a?(Hash)
+            error_msg = @ui.msg_string(:vars_file_non_hash_class_error, {
+              path: path,
+              actual_class: vars.class.to_s,
+            })
+            raise VagrantPlugins::VagrantBosh::Errors::BoshReleaseError, error_msg
+          end
+
+          vars
+        end
+
+        # Values are parsed as YAML so that e.g. numbers and hashes keep their types.
+        def env_vars
+          return {} unless @env_prefix
+
+          prefix = "#{@env_prefix}_"
+
+          ENV.select { |k, _| k.start_with?(prefix) }.each_with_object({}) do |(k, v), vars|
+            begin
+              vars[k.sub(prefix, "")] = YAML.load(v)
+            rescue Psych::SyntaxError
+              vars[k.sub(prefix, "")] = v
+            end
+          end
+        end
+      end
+
+      #~
+    end
+  end
+end
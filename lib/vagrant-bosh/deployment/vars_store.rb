@@ -0,0 +1,193 @@
+require "log4r"
+require "json"
+require "openssl"
+require "securerandom"
+require "fileutils"
+require "vagrant-bosh/errors"
+require "vagrant-bosh/deployment/variables"
+
+module VagrantPlugins
+  module VagrantBosh
+    module Deployment
+      # VarsStore generates values for variables declared in manifest's
+      # `variables` section and persists them in a JSON file on the host
+      # so that re-provisioning uses the same passwords, keys and certificates.
+      # It's applied as a manifest transform and used as a variable source.
+      # Variables already provided by other sources (e.g. vars files, CredHub)
+      # are not generated; they are also used to interpolate definition options.
+      class VarsStore
+        include VagrantPlugins::VagrantBosh::Errors::UiErrors
+
+        PASSWORD_CHARS = [*"a".."z", *"0".."9"]
+
+        EXTENDED_KEY_USAGES = {"server_auth" => "serverAuth", "client_auth" => "clientAuth"}
+
+        def initialize(path, sources, ui)
+          @path = path
+          @sources = sources
+
+          @base_ui = ui
+          @ui = ui.for(:deployment, :vars_store)
+          @logger = Log4r::Logger.new("vagrant::provisioners::bosh::deployment::vars_store")
+        end
+
+        def apply(manifest)
+          definitions = manifest.delete("variables") || []
+
+          generated = definitions.reject do |d|
+            vars.has_key?(d["name"].to_s) || find_provided(d["name"].to_s).last
+          end
+
+          unless generated.empty?
+            # Options may reference provided or previously generated variables
+            interpolator = Variables.new(@sources + [self], @base_ui)
+
+            generated.each do |d|
+              d = interpolator.apply(d)
+              vars[d["name"].to_s] = generate(d)
+            end
+
+            save
+          end
+
+          manifest
+        end
+
+        def find(name)
+          vars.has_key?(name) ? [vars[name], true] : [nil, false]
+        end
+
+        private
+
+        def generate(definition)
+          name, type = definition.values_at("name", "type")
+          options = definition["options"] || {}
+
+          @ui.msg(:generate, name: name, type: type)
+
+          case type
+          when "password"    then generate_password
+          when "rsa"         then generate_rsa
+          when "ssh"         then generate_ssh
+          when "certificate" then generate_certificate(name, options)
+          else raise_error(:unknown_type_error, name: name, type: type)
+          end
+        end
+
+        def generate_password
+          Array.new(20) { PASSWORD_CHARS[SecureRandom.random_number(PASSWORD_CHARS.size)] }.join
+        end
+
+        def generate_rsa
+          key = OpenSSL::PKey::RSA.new(2048)
+          {"private_key" => key.to_pem, "public_key" => key.public_key.to_pem}
+        end
+
+        def generate_ssh
+          key = OpenSSL::PKey::RSA.new(2048)
+
+          # Public key in authorized_keys format: ssh-rsa <base64 of type, e, n>
+          blob = ssh_string("ssh-rsa") + ssh_mpint(key.e) + ssh_mpint(key.n)
+
+          {
+            "private_key" => key.to_pem,
+            "public_key" => "ssh-rsa #{[blob].pack("m0")}",
+            "public_key_fingerprint" => OpenSSL::Digest::MD5.hexdigest(blob).scan(/../).join(":"),
+          }
+        end
+
+        def ssh_string(str)
+          [str.bytesize].pack("N") + str
+        end
+
+        def ssh_mpint(bn)
+          bytes = bn.to_s(2)
+          bytes = "\x00".b + bytes if bytes.getbyte(0) & 0x80 != 0
+          ssh_string(bytes)
+        end
+
+        def generate_certificate(name, options)
+          key = OpenSSL::PKey::RSA.new(2048)
+
+          ca_name = options["ca"]
+          is_ca = !!options["is_ca"]
+
+          if ca_name
+            ca, found = find_provided(ca_name.to_s)
+            ca, found = find(ca_name.to_s) unless found
+            raise_error(:missing_ca_error, name: name, ca: ca_name) unless found && ca.is_a?(Hash)
+
+            ca_cert = OpenSSL::X509::Certificate.new(ca["certificate"])
+            ca_key = OpenSSL::PKey::RSA.new(ca["private_key"])
+          elsif !is_ca
+            raise_error(:missing_ca_error, name: name, ca: "")
+          end
+
+          cert = OpenSSL::X509::Certificate.new
+          cert.version = 2
+          cert.serial = OpenSSL::BN.rand(128)
+          cert.subject = OpenSSL::X509::Name.new([["CN", options["common_name"] || name.to_s]])
+          cert.issuer = ca_cert ? ca_cert.subject : cert.subject
+          cert.public_key = key.public_key
+          cert.not_before = Time.now - 60
+          cert.not_after = Time.now + (options["duration"] || 365).to_i * 24 * 60 * 60
+
+          ext = OpenSSL::X509::ExtensionFactory.new
+          ext.subject_certificate = cert
+          ext.issuer_certificate = ca_cert || cert
+
+          cert.add_extension(ext.create_extension("basicConstraints", is_ca ? "CA:TRUE" : "CA:FALSE", true))
+
+          if is_ca
+            cert.add_extension(ext.create_extension("keyUsage", "keyCertSign,cRLSign", true))
+          else
+            cert.add_extension(ext.create_extension("keyUsage", "digitalSignature,keyEncipherment", true))
+          end
+
+          usages = Array(options["extended_key_usage"]).map { |u| EXTENDED_KEY_USAGES[u.to_s] }.compact
+          cert.add_extension(ext.create_extension("extendedKeyUsage", usages.join(","))) unless usages.empty?
+
+          alt_names = Array(options["alternative_names"]).map do |alt_name|
+            alt_name.to_s =~ /\A[\d\.]+\z|:/ ? "IP:#{alt_name}" : "DNS:#{alt_name}"
+          end
+          cert.add_extension(ext.create_extension("subjectAltName", alt_names.join(","))) unless alt_names.empty?
+
+          cert.sign(ca_key || key, OpenSSL::Digest::SHA256.new)
+
+          {
+            "ca" => (ca_cert || cert).to_pem,
+            "certificate" => cert.to_pem,
+            "private_key" => key.to_pem,
+          }
+        end
+
+        def find_provided(name)
+          @sources.each do |source|
+            value, found = source.find(name)
+            return [value, true] if found
+          end
+
+          [nil, false]
+        end
+
+        def vars
+          return @vars if @vars
+
+          begin
+            @vars = File.exist?(@path) ? JSON.parse(File.read(@path)) : {}
+          rescue SystemCallError, JSON::ParserError => e
+            raise_error(:load_error, path: @path, details: e.inspect)
+          end
+        end
+
+        # Store contains secrets hence it's only readable by the user
+        def save
+          FileUtils.mkdir_p(File.dirname(@path))
+          File.open(@path, "w", 0600) { |f| f.write(JSON.pretty_generate(vars)) }
+        end
+      end
+
+      #~
+    end
+  end
+end
@@ -4,6 +4,17 @@ module VagrantPlugins
   module VagrantBosh
     module Errors
       class BoshReleaseError < StandardError; end
+
+      # UiErrors raises BoshReleaseError with a message
+      # looked up via @ui of the including class.
+      module UiErrors
+        private
+
+        def raise_error(key, hash)
+          error_msg = @ui.msg_string(key, hash)
+          raise BoshReleaseError, error_msg
+        end
+      end
     end
   end
 end
@@ -5,8 +5,10 @@ require "vagrant-bosh/asset_uploader"
 require "vagrant-bosh/bootstrapper"
 require "vagrant-bosh/provisioner_tracker"
 require "vagrant-bosh/deployment/release_uploader"
+require "vagrant-bosh/deployment/release_download_pool"
 require "vagrant-bosh/deployment/uploadable_release_factory"
 require "vagrant-bosh/deployment/manifest_factory"
+require "vagrant-bosh/deployment/applied_manifest"
 
 module VagrantPlugins
   module VagrantBosh
@@ -28,18 +30,47 @@ module VagrantPlugins
 
         release_uploader = Deployment::ReleaseUploader.new(
           machine, 
+          asset_uploader,
           machine_ui,
         )
 
         uploadable_release_factory = Deployment::UploadableReleaseFactory.new(
           config.synced_releases_dir,
+          machine.env.home_path.join("cache", "bosh-releases").to_s,
           release_uploader,
+          Deployment::ReleaseDownloadPool.new(
+            config.release_download_concurrency,
+            config.release_download_limit_rate,
+            machine_ui,
+          ),
           config.create_release_cmd,
+          config.require_release_checksums,
+          config.oci_credentials,
           machine_ui,
         )
 
         manifest_factory = Deployment::ManifestFactory.new(
           uploadable_release_factory,
+          Deployment::ManifestPreprocessor.for(
+            config.manifest_preprocessor, 
+            machine.env.root_path, 
+            machine_ui,
+          ),
+          config.cloud_config,
+          config.ops_files.map { |path| File.expand_path(path, machine.env.root_path) },
+          config.vars_files.map { |path| File.expand_path(path, machine.env.root_path) },
+          config.vars_env,
+          config.vars,
+          (config.vars_store ?
+            File.expand_path(config.vars_store, machine.env.root_path) :
+            machine.data_dir.join("bosh", "vars-store.json").to_s),
+          (Hash[config.credhub.map { |k, v| [k.to_s, v] }] if config.credhub),
+          config.strict_manifest,
+          machine_ui,
+        )
+
+        applied_manifest = Deployment::AppliedManifest.new(
+          machine.data_dir.join("bosh", "applied-manifest.yml").to_s,
           machine_ui,
         )
 
@@ -49,6 +80,7 @@ module VagrantPlugins
           asset_uploader, 
           provisioner_tracker,
           manifest_factory, 
+          applied_manifest,
         )
 
         logger = Log4r::Logger.new("vagrant::provisioners::bosh")